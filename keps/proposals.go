@@ -4,10 +4,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/chuckha/kepview/prrs"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 )
@@ -48,11 +51,31 @@ type Proposal struct {
 	CreationDate      time.Time `yaml:"creation-date"`
 	LastUpdated       time.Time `yaml:"last-updated"`
 	Status            string
-	SeeAlso           []string `yaml:"see-also"`
+	SeeAlso           []string          `yaml:"see-also"`
+	Editor            string            `yaml:"editor,omitempty"`
+	Replaces          []string          `yaml:"replaces,omitempty"`
+	SupersededBy      []string          `yaml:"superseded-by,omitempty"`
+	Stage             string            `yaml:"stage,omitempty"`
+	LatestMilestone   string            `yaml:"latest-milestone,omitempty"`
+	Milestone         map[string]string `yaml:"milestone,omitempty"`
+	FeatureGates      []FeatureGate     `yaml:"feature-gates,omitempty"`
+
+	// Body is the markdown content of the KEP after its YAML front-matter.
+	Body []byte `yaml:"-"`
+	// Extra holds front-matter keys that are not known fields of Proposal,
+	// so that Writer can round-trip them back out unchanged.
+	Extra map[string]interface{} `yaml:"-"`
 
 	Filename string `yaml:"-"`
 }
 
+// FeatureGate is a feature gate a KEP introduces or relies on, along with
+// the components that implement it.
+type FeatureGate struct {
+	Name       string   `yaml:"name"`
+	Components []string `yaml:",flow"`
+}
+
 func (p *Proposal) Filter(key, value string) bool {
 	switch key {
 	case "author":
@@ -83,40 +106,281 @@ func (p *Parser) Parse(in io.Reader) (*Proposal, error) {
 	scanner := bufio.NewScanner(in)
 	count := 0
 	metadata := []byte{}
+	body := []byte{}
 	for scanner.Scan() {
 		line := scanner.Text() + "\n"
-		if strings.Contains(line, "---") {
+		if count < 2 && strings.Contains(line, "---") {
 			count++
 			continue
 		}
-		if count == 2 {
-			break
+		if count < 2 {
+			metadata = append(metadata, []byte(line)...)
+			continue
 		}
-		metadata = append(metadata, []byte(line)...)
-
+		body = append(body, []byte(line)...)
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	proposal := &Proposal{}
-	err := yaml.Unmarshal(metadata, proposal)
-	return proposal, errors.WithStack(err)
+	if err := yaml.Unmarshal(metadata, proposal); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	proposal.Body = body
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(metadata, &raw); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for key, value := range raw {
+		if isKnownProposalKey(key) {
+			continue
+		}
+		if proposal.Extra == nil {
+			proposal.Extra = map[string]interface{}{}
+		}
+		proposal.Extra[key] = value
+	}
+
+	return proposal, nil
+}
+
+// Writer writes a Proposal back out as markdown with YAML front-matter,
+// preserving its Body and any unknown front-matter keys captured in Extra.
+type Writer struct {
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+func (w *Writer) Write(out io.Writer, p *Proposal) error {
+	meta := p.Metadata()
+	var slice yaml.MapSlice
+	for _, key := range proposalKeyOrder {
+		value := meta[key]
+		if isZero(value) {
+			continue
+		}
+		slice = append(slice, yaml.MapItem{Key: key, Value: value})
+	}
+
+	extraKeys := make([]string, 0, len(p.Extra))
+	for key := range p.Extra {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		slice = append(slice, yaml.MapItem{Key: key, Value: p.Extra[key]})
+	}
+
+	data, err := yaml.Marshal(slice)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := fmt.Fprintf(out, "---\n%s---\n", data); err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = out.Write(p.Body)
+	return errors.WithStack(err)
+}
+
+func isZero(v interface{}) bool {
+	switch value := v.(type) {
+	case string:
+		return value == ""
+	case []string:
+		return len(value) == 0
+	case map[string]string:
+		return len(value) == 0
+	case []FeatureGate:
+		return len(value) == 0
+	case time.Time:
+		return value.IsZero()
+	}
+	return false
 }
 
 var validProposalStatus = []string{"provisional", "implementable", "implemented", "deferred", "rejected", "withdrawn", "replaced"}
 
-func Validate(p *Proposal) []error {
+var validStages = []string{"alpha", "beta", "stable"}
+
+var validFeatureGateComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kubelet", "kube-proxy"}
+
+// KindKEP is the Document kind a *Proposal reports itself as.
+const KindKEP = "KEP"
+
+// Document is anything that can be run through the validator registry:
+// a kind used to look up its registered validators, plus its metadata for
+// validators that only need to inspect it generically.
+type Document interface {
+	Kind() string
+	Metadata() map[string]interface{}
+}
+
+func (p *Proposal) Kind() string { return KindKEP }
+
+func (p *Proposal) Metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"title":              p.Title,
+		"authors":            p.Authors,
+		"owning-sig":         p.OwningSIG,
+		"participating-sigs": p.ParticipatingSIGs,
+		"reviewers":          p.Reviewers,
+		"approvers":          p.Approvers,
+		"editor":             p.Editor,
+		"creation-date":      p.CreationDate,
+		"last-updated":       p.LastUpdated,
+		"status":             p.Status,
+		"see-also":           p.SeeAlso,
+		"replaces":           p.Replaces,
+		"superseded-by":      p.SupersededBy,
+		"stage":              p.Stage,
+		"latest-milestone":   p.LatestMilestone,
+		"milestone":          p.Milestone,
+		"feature-gates":      p.FeatureGates,
+	}
+}
+
+// proposalKeyOrder is the stable front-matter key order used by Writer, and
+// doubles as the set of known Proposal fields used to separate front-matter
+// keys from the catch-all Extra map.
+var proposalKeyOrder = []string{
+	"title", "authors", "owning-sig", "participating-sigs", "reviewers", "approvers",
+	"editor", "creation-date", "last-updated", "status", "see-also", "replaces",
+	"superseded-by", "stage", "latest-milestone", "milestone", "feature-gates",
+}
+
+func isKnownProposalKey(key string) bool {
+	for _, k := range proposalKeyOrder {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+type validatorEntry struct {
+	kind string
+	fn   func(Document) []error
+}
+
+var validatorRegistry []validatorEntry
+
+// RegisterValidator registers fn to run against every Document whose Kind
+// matches kind. Registering against kind "*" runs fn against every
+// Document regardless of kind. Validators run in registration order.
+func RegisterValidator(kind string, fn func(Document) []error) {
+	validatorRegistry = append(validatorRegistry, validatorEntry{kind, fn})
+}
+
+// ValidateDocument runs every validator registered for d.Kind(), plus every
+// validator registered for "*", in registration order.
+func ValidateDocument(d Document) []error {
+	var errs []error
+	for _, v := range validatorRegistry {
+		if v.kind == d.Kind() || v.kind == "*" {
+			errs = append(errs, v.fn(d)...)
+		}
+	}
+	return errs
+}
+
+func init() {
+	RegisterValidator(KindKEP, validateTitle)
+	RegisterValidator(KindKEP, validateAuthors)
+	RegisterValidator(KindKEP, validateOwningSIG)
+	RegisterValidator(KindKEP, validateReviewers)
+	RegisterValidator(KindKEP, validateApprovers)
+	RegisterValidator(KindKEP, validateDates)
+	RegisterValidator(KindKEP, validateStatusTransition)
+	RegisterValidator(KindKEP, validateStage)
+	RegisterValidator(KindKEP, validateFeatureGates)
+}
+
+// asProposal recovers the *Proposal backing a Document registered under
+// KindKEP. A Document whose Kind() returns KindKEP but whose concrete type
+// isn't *Proposal is a mismatch between a caller's registration and its
+// implementation, not a validation failure of any proposal's content, so it
+// comes back as a single error rather than a panic.
+func asProposal(d Document) (*Proposal, error) {
+	p, ok := d.(*Proposal)
+	if !ok {
+		return nil, fmt.Errorf("expected a *keps.Proposal for kind %q, got %T", KindKEP, d)
+	}
+	return p, nil
+}
+
+func validateTitle(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
 	var field fieldValidator
 	field.isNonEmpty("title", p.Title)
+	return []error(field)
+}
+
+func validateAuthors(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonEmptySlice("authors list", p.Authors)
+	return []error(field)
+}
+
+func validateOwningSIG(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonEmpty("owning-sig", p.OwningSIG)
+	return []error(field)
+}
+
+func validateReviewers(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonEmptySlice("reviewers list", p.Reviewers)
+	return []error(field)
+}
+
+func validateApprovers(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonEmptySlice("approvers list", p.Approvers)
+	return []error(field)
+}
+
+func validateDates(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonZeroTime("creation date", p.CreationDate)
 	field.isNonZeroTime("last updated date", p.LastUpdated)
 	if !p.LastUpdated.IsZero() && !p.CreationDate.IsZero() {
 		field.isAfter("last updated date", "creation date", p.LastUpdated, p.CreationDate)
 	}
+	return []error(field)
+}
+
+func validateStatusTransition(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
 	field.isNonEmpty("status", p.Status)
 	if p.Status != "" {
 		field.isOneOf("status", p.Status, validProposalStatus)
@@ -124,6 +388,65 @@ func Validate(p *Proposal) []error {
 	return []error(field)
 }
 
+func validateStage(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
+	if p.Stage == "" {
+		return nil
+	}
+	field.isOneOf("stage", p.Stage, validStages)
+	if p.LatestMilestone != "" && len(p.Milestone) > 0 {
+		field.isOneOfMap("latest-milestone", p.Stage, p.LatestMilestone, p.Milestone)
+	}
+	validateStageProgression(p.Stage, p.Milestone, &field)
+	return []error(field)
+}
+
+func validateFeatureGates(d Document) []error {
+	p, err := asProposal(d)
+	if err != nil {
+		return []error{err}
+	}
+	var field fieldValidator
+	for _, fg := range p.FeatureGates {
+		field.isNonEmpty("feature-gate name", fg.Name)
+		field.hasValidComponent("feature-gate components", fg.Components, validFeatureGateComponents)
+	}
+	return []error(field)
+}
+
+// Validate is a thin wrapper around ValidateDocument kept for backward
+// compatibility with existing callers.
+func Validate(p *Proposal) []error {
+	return ValidateDocument(p)
+}
+
+// validateStageProgression checks that a proposal's milestones advance
+// monotonically: if it has reached stage, every earlier stage present in
+// milestone must have an earlier milestone value than the stage after it.
+func validateStageProgression(stage string, milestone map[string]string, field *fieldValidator) {
+	idx := -1
+	for i, s := range validStages {
+		if s == stage {
+			idx = i
+		}
+	}
+	for i := 0; i < idx; i++ {
+		earlier, ok := milestone[validStages[i]]
+		if !ok {
+			continue
+		}
+		later, ok := milestone[validStages[i+1]]
+		if !ok {
+			continue
+		}
+		field.isSemverBefore(validStages[i]+" milestone", validStages[i+1]+" milestone", earlier, later)
+	}
+}
+
 type fieldValidator []error
 
 func (fv *fieldValidator) isNonEmpty(field, value string) {
@@ -158,3 +481,101 @@ func (fv *fieldValidator) isAfter(field1, field2 string, value1, value2 time.Tim
 		*fv = append(*fv, fmt.Errorf("%s must be later than %s", field1, field2))
 	}
 }
+
+// isOneOfMap checks that m[key] equals value, the way isOneOf checks a
+// value against a fixed list of valid values.
+func (fv *fieldValidator) isOneOfMap(field, key, value string, m map[string]string) {
+	expected, ok := m[key]
+	if !ok {
+		*fv = append(*fv, fmt.Errorf("%s stage %q has no milestone entry", field, key))
+		return
+	}
+	if expected != value {
+		*fv = append(*fv, fmt.Errorf("'%s' is not a valid %s for stage %q. Valid option is '%s'", value, field, key, expected))
+	}
+}
+
+// isSemverBefore checks that value1 (a "vX.Y" Kubernetes release) is before
+// value2.
+func (fv *fieldValidator) isSemverBefore(field1, field2, value1, value2 string) {
+	before, err := semverBefore(value1, value2)
+	if err != nil {
+		*fv = append(*fv, err)
+		return
+	}
+	if !before {
+		*fv = append(*fv, fmt.Errorf("%s (%s) must be before %s (%s)", field1, value1, field2, value2))
+	}
+}
+
+func (fv *fieldValidator) hasValidComponent(field string, components []string, validComponents []string) {
+	for _, c := range components {
+		if Contains(validComponents, c) {
+			return
+		}
+	}
+	*fv = append(*fv, fmt.Errorf("%s must include at least one of '%s'", field, strings.Join(validComponents, "', '")))
+}
+
+// semverBefore reports whether a "vX.Y" Kubernetes release version is
+// earlier than another.
+func semverBefore(a, b string) (bool, error) {
+	aMajor, aMinor, err := parseReleaseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	bMajor, bMinor, err := parseReleaseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	if aMajor != bMajor {
+		return aMajor < bMajor, nil
+	}
+	return aMinor < bMinor, nil
+}
+
+func parseReleaseVersion(v string) (int, int, error) {
+	var major, minor int
+	if _, err := fmt.Sscanf(v, "v%d.%d", &major, &minor); err != nil {
+		return 0, 0, fmt.Errorf("%q is not a valid release version, expected format 'vX.Y'", v)
+	}
+	return major, minor, nil
+}
+
+// stagesUpTo returns every stage a proposal must have PRR approval for in
+// order to have reached stage, e.g. a proposal in "beta" must also have
+// alpha approval.
+func stagesUpTo(stage string) []string {
+	for i, s := range validStages {
+		if s == stage {
+			return validStages[:i+1]
+		}
+	}
+	return nil
+}
+
+// ValidateWithPRR runs Validate and additionally cross-checks the proposal
+// against its sibling PRR approval file, which by convention lives at
+// <kepDir>/prod-readiness/<owning-sig>/<kepNumber>.yaml. prrApprovers is the
+// owning SIG's set of valid PRR approvers. Errors from both checks are
+// returned in the same []error slice so existing callers of Validate keep
+// working unchanged. A proposal with no stage gets only the Validate checks;
+// its PRR approval file is not read.
+func ValidateWithPRR(p *Proposal, kepDir, kepNumber string, prrApprovers []string) []error {
+	errs := Validate(p)
+	if p.Stage == "" {
+		return errs
+	}
+	prrPath := filepath.Join(kepDir, "prod-readiness", p.OwningSIG, kepNumber+".yaml")
+	f, err := os.Open(prrPath)
+	if err != nil {
+		return append(errs, errors.Wrapf(err, "failed to open PRR approval file %s", prrPath))
+	}
+	defer f.Close()
+
+	approval, err := prrs.NewParser().Parse(f)
+	if err != nil {
+		return append(errs, errors.WithStack(err))
+	}
+	return append(errs, prrs.Validate(approval, stagesUpTo(p.Stage), prrApprovers)...)
+}
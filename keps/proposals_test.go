@@ -1,10 +1,15 @@
 package keps_test
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/chuckha/kepview/keps"
 )
@@ -248,3 +253,287 @@ status: foo
 
 	}
 }
+
+func validBaseContent(extra string) string {
+	return `---
+title: test
+authors:
+- "@jpbetz"
+owning-sig: sig-api-machinery
+reviewers:
+  - "@deads2k"
+approvers:
+  - "@deads2k"
+creation-date: 2018-04-15
+last-updated: 2018-04-24
+status: implementable
+` + extra
+}
+
+func TestProposalStageValidation(t *testing.T) {
+	testcases := []struct {
+		name           string
+		content        string
+		expectedErrors []error
+	}{
+		{
+			name: "skipped stage is not required to have a milestone entry",
+			content: validBaseContent(`stage: beta
+latest-milestone: v1.24
+milestone:
+  beta: v1.24
+`),
+			expectedErrors: nil,
+		},
+		{
+			name: "mismatched latest-milestone",
+			content: validBaseContent(`stage: beta
+latest-milestone: v1.23
+milestone:
+  alpha: v1.20
+  beta: v1.24
+`),
+			expectedErrors: []error{fmt.Errorf("'v1.23' is not a valid latest-milestone for stage \"beta\". Valid option is 'v1.24'")},
+		},
+		{
+			name: "stages do not advance monotonically",
+			content: validBaseContent(`stage: beta
+latest-milestone: v1.20
+milestone:
+  alpha: v1.24
+  beta: v1.20
+`),
+			expectedErrors: []error{fmt.Errorf("alpha milestone (v1.24) must be before beta milestone (v1.20)")},
+		},
+		{
+			name: "unknown feature-gate component",
+			content: validBaseContent(`feature-gates:
+  - name: MyFeature
+    components: ["not-a-component"]
+`),
+			expectedErrors: []error{fmt.Errorf("feature-gate components must include at least one of 'kube-apiserver', 'kube-controller-manager', 'kube-scheduler', 'kubelet', 'kube-proxy'")},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := keps.NewParser()
+			kep, err := p.Parse(strings.NewReader(tc.content))
+			if err != nil {
+				t.Fatalf("error parsing proposal: %v", err)
+			}
+			got := keps.Validate(kep)
+			if !reflect.DeepEqual(got, tc.expectedErrors) {
+				t.Errorf("expected errors:\n%v\ngot:\n%v", tc.expectedErrors, got)
+			}
+		})
+	}
+}
+
+func TestParserCapturesBody(t *testing.T) {
+	content := `---
+title: test
+authors:
+  - "@jpbetz"
+owning-sig: sig-api-machinery
+reviewers:
+  - "@deads2k"
+approvers:
+  - "@deads2k"
+creation-date: 2018-04-15
+last-updated: 2018-04-24
+status: provisional
+---
+## Summary
+
+This is the body.
+`
+	p := keps.NewParser()
+	kep, err := p.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	wantBody := "## Summary\n\nThis is the body.\n"
+	if string(kep.Body) != wantBody {
+		t.Fatalf("expected body:\n%q\ngot:\n%q", wantBody, string(kep.Body))
+	}
+}
+
+func TestParserPreservesUnknownKeys(t *testing.T) {
+	content := `---
+title: test
+authors:
+  - "@jpbetz"
+owning-sig: sig-api-machinery
+reviewers:
+  - "@deads2k"
+approvers:
+  - "@deads2k"
+creation-date: 2018-04-15
+last-updated: 2018-04-24
+status: provisional
+kep-number: "1234"
+---`
+	p := keps.NewParser()
+	kep, err := p.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if kep.Extra["kep-number"] != "1234" {
+		t.Fatalf("expected kep-number to be preserved in Extra, got %v", kep.Extra)
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	content := `---
+title: test
+authors:
+  - "@jpbetz"
+owning-sig: sig-api-machinery
+reviewers:
+  - "@deads2k"
+approvers:
+  - "@deads2k"
+creation-date: 2018-04-15T00:00:00Z
+last-updated: 2018-04-24T00:00:00Z
+status: provisional
+kep-number: "1234"
+---
+## Summary
+
+This is the body.
+`
+	p := keps.NewParser()
+	kep, err := p.Parse(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	var buf bytes.Buffer
+	w := keps.NewWriter()
+	if err := w.Write(&buf, kep); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	reparsed, err := keps.NewParser().Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("error re-parsing written proposal: %+v", err)
+	}
+	if !reflect.DeepEqual(kep, reparsed) {
+		t.Fatalf("round-tripped proposal does not match original:\nwant: %+v\ngot:  %+v", kep, reparsed)
+	}
+
+	// Writing twice must produce byte-identical output.
+	var buf2 bytes.Buffer
+	if err := w.Write(&buf2, kep); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if buf.String() != buf2.String() {
+		t.Fatalf("Write is not deterministic:\nfirst:  %q\nsecond: %q", buf.String(), buf2.String())
+	}
+}
+
+func validTestProposal(stage string) *keps.Proposal {
+	return &keps.Proposal{
+		Title:        "test",
+		Authors:      []string{"@jpbetz"},
+		OwningSIG:    "sig-api-machinery",
+		Reviewers:    []string{"@deads2k"},
+		Approvers:    []string{"@deads2k"},
+		CreationDate: time.Date(2018, 4, 15, 0, 0, 0, 0, time.UTC),
+		LastUpdated:  time.Date(2018, 4, 24, 0, 0, 0, 0, time.UTC),
+		Status:       "provisional",
+		Stage:        stage,
+	}
+}
+
+func writePRRFile(t *testing.T, kepDir, sig, kepNumber, contents string) {
+	t.Helper()
+	dir := filepath.Join(kepDir, "prod-readiness", sig)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create PRR dir: %v", err)
+	}
+	path := filepath.Join(dir, kepNumber+".yaml")
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write PRR file: %v", err)
+	}
+}
+
+func TestValidateWithPRR(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		writePRRFile(t, dir, "sig-api-machinery", "1234", `---
+alpha:
+  approver: "@deads2k"
+---`)
+		errs := keps.ValidateWithPRR(validTestProposal("alpha"), dir, "1234", []string{"@deads2k"})
+		if len(errs) != 0 {
+			t.Fatalf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("missing PRR file", func(t *testing.T) {
+		dir := t.TempDir()
+		errs := keps.ValidateWithPRR(validTestProposal("alpha"), dir, "1234", []string{"@deads2k"})
+		if len(errs) != 1 {
+			t.Fatalf("expected exactly one error, got %v", errs)
+		}
+		if !strings.Contains(errs[0].Error(), "failed to open PRR approval file") {
+			t.Fatalf("expected an error about opening the PRR file, got %q", errs[0])
+		}
+	})
+
+	t.Run("beta stage requires alpha and beta approval", func(t *testing.T) {
+		dir := t.TempDir()
+		writePRRFile(t, dir, "sig-api-machinery", "1234", `---
+alpha:
+  approver: "@deads2k"
+---`)
+		errs := keps.ValidateWithPRR(validTestProposal("beta"), dir, "1234", []string{"@deads2k"})
+		if len(errs) != 1 {
+			t.Fatalf("expected exactly one error, got %v", errs)
+		}
+		wantErr := `PRR approval is missing required stage "beta"`
+		if errs[0].Error() != wantErr {
+			t.Fatalf("expected error %q, got %q", wantErr, errs[0])
+		}
+	})
+}
+
+type fakeDocument struct {
+	kind string
+}
+
+func (f *fakeDocument) Kind() string                     { return f.kind }
+func (f *fakeDocument) Metadata() map[string]interface{} { return nil }
+
+func TestValidatorRegistryDispatchesInRegistrationOrder(t *testing.T) {
+	var calls []string
+
+	keps.RegisterValidator("test-kind", func(d keps.Document) []error {
+		calls = append(calls, "kind-specific")
+		return nil
+	})
+	keps.RegisterValidator("*", func(d keps.Document) []error {
+		calls = append(calls, "wildcard")
+		if d.Kind() != "test-kind" {
+			return nil
+		}
+		return []error{fmt.Errorf("wildcard saw %s", d.Kind())}
+	})
+
+	errs := keps.ValidateDocument(&fakeDocument{kind: "test-kind"})
+
+	if !reflect.DeepEqual(calls, []string{"kind-specific", "wildcard"}) {
+		t.Fatalf("expected validators to run in registration order, got %v", calls)
+	}
+	if len(errs) != 1 || errs[0].Error() != "wildcard saw test-kind" {
+		t.Fatalf("expected the wildcard validator's error to be returned, got %v", errs)
+	}
+}
+
+func TestValidateDocumentDoesNotPanicOnWrongConcreteType(t *testing.T) {
+	errs := keps.ValidateDocument(&fakeDocument{kind: keps.KindKEP})
+	if len(errs) == 0 {
+		t.Fatal("expected an error for a Document claiming KindKEP without being a *keps.Proposal, got none")
+	}
+}
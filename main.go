@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chuckha/kepview/keps"
+	"github.com/pkg/errors"
+)
+
+// Opener opens a file for reading. It exists so tests can substitute a
+// fake filesystem.
+type Opener interface {
+	Open(path string) (*os.File, error)
+}
+
+// Parser parses a KEP proposal out of a file's contents.
+type Parser interface {
+	Parse(reader io.Reader) (*keps.Proposal, error)
+}
+
+// Logger is the subset of logging EnhancementFinder needs.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+}
+
+type osOpener struct{}
+
+func (o *osOpener) Open(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+type stdLogger struct {
+	debug bool
+}
+
+func (l *stdLogger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		log.Printf(format, args...)
+	}
+}
+
+// filenameFilter reports whether a file should be skipped during a find.
+type filenameFilter func(name string) bool
+
+// defaultFilters returns the filters kepctl applies to every enhancement
+// directory: skip READMEs, OWNERS files, templates, and anything that
+// isn't markdown.
+func defaultFilters() []filenameFilter {
+	return []filenameFilter{
+		isReadme,
+		isOwners,
+		isTemplate,
+		notMarkdown,
+	}
+}
+
+func isReadme(name string) bool {
+	return strings.EqualFold(name, "README.md")
+}
+
+func isOwners(name string) bool {
+	return strings.EqualFold(name, "OWNERS")
+}
+
+func isTemplate(name string) bool {
+	return strings.Contains(strings.ToLower(name), "template")
+}
+
+func notMarkdown(name string) bool {
+	return filepath.Ext(name) != ".md"
+}
+
+// finderOpts configures an EnhancementFinder.
+type finderOpts func(*EnhancementFinder)
+
+// WithConcurrency bounds the number of files FindStream parses at once.
+func WithConcurrency(n int) finderOpts {
+	return func(e *EnhancementFinder) {
+		if n > 0 {
+			e.concurrency = n
+		}
+	}
+}
+
+// EnhancementFinder walks a directory tree looking for KEP proposals.
+type EnhancementFinder struct {
+	opener          Opener
+	parser          Parser
+	log             Logger
+	filenameFilters []filenameFilter
+	concurrency     int
+}
+
+// NewEnhancementFinder builds an EnhancementFinder backed by the real
+// filesystem.
+func NewEnhancementFinder(log Logger, opts ...finderOpts) *EnhancementFinder {
+	e := &EnhancementFinder{
+		opener:          &osOpener{},
+		parser:          keps.NewParser(),
+		log:             log,
+		filenameFilters: defaultFilters(),
+		concurrency:     4,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+func (e *EnhancementFinder) ignore(name string) bool {
+	for _, filter := range e.filenameFilters {
+		if filter(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *EnhancementFinder) parseFile(path string) (*keps.Proposal, error) {
+	f, err := e.opener.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	proposal, err := e.parser.Parse(f)
+	if err != nil {
+		e.log.Debugf("failed to parse %s: %v", path, err)
+		return nil, nil
+	}
+	proposal.Filename = path
+	return proposal, nil
+}
+
+// FindStream walks root on a bounded pool of workers and streams parsed
+// proposals back on the returned channel as they're found. It honors
+// ctx.Done() between directory entries and closes both channels once the
+// walk (and every in-flight parse) has finished.
+func (e *EnhancementFinder) FindStream(ctx context.Context, root string) (<-chan *keps.Proposal, <-chan error) {
+	proposals := make(chan *keps.Proposal)
+	errs := make(chan error)
+
+	concurrency := e.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	go func() {
+		defer close(proposals)
+		defer close(errs)
+
+		paths := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for path := range paths {
+					proposal, err := e.parseFile(path)
+					if err != nil {
+						select {
+						case errs <- err:
+						case <-ctx.Done():
+						}
+						continue
+					}
+					if proposal == nil {
+						continue
+					}
+					select {
+					case proposals <- proposal:
+					case <-ctx.Done():
+					}
+				}
+			}()
+		}
+
+		walkErr := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if e.ignore(info.Name()) {
+				return nil
+			}
+			select {
+			case paths <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		close(paths)
+		wg.Wait()
+
+		if walkErr != nil && walkErr != context.Canceled {
+			select {
+			case errs <- walkErr:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return proposals, errs
+}
+
+// Find walks root and returns every enhancement proposal found under it,
+// draining FindStream into a *keps.Proposals. On the first error it stops,
+// cancels the rest of the walk, and returns the proposals collected so far
+// along with that error.
+func (e *EnhancementFinder) Find(ctx context.Context, root string) (*keps.Proposals, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	out := &keps.Proposals{}
+	proposalCh, errCh := e.FindStream(ctx, root)
+	for proposalCh != nil || errCh != nil {
+		select {
+		case proposal, ok := <-proposalCh:
+			if !ok {
+				proposalCh = nil
+				continue
+			}
+			out.AddProposal(proposal)
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			return out, err
+		}
+	}
+	return out, nil
+}
+
+func main() {
+	root := "."
+	if len(os.Args) > 1 {
+		root = os.Args[1]
+	}
+
+	finder := NewEnhancementFinder(&stdLogger{})
+	proposals, err := finder.Find(context.Background(), root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, p := range *proposals {
+		log.Println(p.Filename)
+	}
+}
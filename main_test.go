@@ -1,25 +1,19 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
 	"github.com/chuckha/kepview/keps"
 )
 
-type info struct {
-	name string
-}
-
-func (i *info) Name() string       { return i.name }
-func (i *info) Size() int64        { return 0 }
-func (i *info) Mode() os.FileMode  { return os.FileMode(100) }
-func (i *info) ModTime() time.Time { return time.Date(2019, 4, 20, 0, 0, 0, 0, nil) }
-func (i *info) IsDir() bool        { return false }
-func (i *info) Sys() interface{}   { return struct{}{} }
-
 type myparser struct {
 	proposal *keps.Proposal
 }
@@ -73,11 +67,11 @@ func TestFindEnhancementsIgnores(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, tc.filename, "")
 			ef := defaultTestEnhancementFinder()
-			out := &keps.Proposals{}
-			fe := ef.Find(out)
-			i := &info{tc.filename}
-			if err := fe("test", i, nil); err != nil {
+			out, err := ef.Find(context.Background(), dir)
+			if err != nil {
 				t.Fatalf("%+v", err)
 			}
 			if len(*out) != 0 {
@@ -99,20 +93,102 @@ func TestEnhancementFinder(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFile(t, dir, tc.filename, "")
 			ef := defaultTestEnhancementFinder()
 			ef.parser = &myparser{&keps.Proposal{}}
-			out := &keps.Proposals{}
-			fe := ef.Find(out)
-			i := &info{tc.filename}
-			if err := fe("test", i, nil); err != nil {
+			out, err := ef.Find(context.Background(), dir)
+			if err != nil {
 				t.Fatalf("%+v", err)
 			}
 			if len(*out) != 1 {
 				t.Fatalf("Expected 1 item but found: %v", out)
 			}
-			if (*out)[0].Filename != "test" {
-				t.Fatalf("expected proposal to have a filename of %q but had %q", tc.filename, (*out)[0].Filename)
+			wantFilename := filepath.Join(dir, tc.filename)
+			if (*out)[0].Filename != wantFilename {
+				t.Fatalf("expected proposal to have a filename of %q but had %q", wantFilename, (*out)[0].Filename)
 			}
 		})
 	}
 }
+
+const validKEPFrontMatter = `---
+title: test
+authors:
+  - "@jpbetz"
+owning-sig: sig-api-machinery
+reviewers:
+  - "@deads2k"
+approvers:
+  - "@deads2k"
+creation-date: 2018-04-15
+last-updated: 2018-04-24
+status: provisional
+---
+`
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+}
+
+func newRealEnhancementFinder(concurrency int) *EnhancementFinder {
+	return &EnhancementFinder{
+		opener:          &osOpener{},
+		parser:          keps.NewParser(),
+		log:             &mylogger{},
+		filenameFilters: defaultFilters(),
+		concurrency:     concurrency,
+	}
+}
+
+func TestFindStreamAggregatesConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	const n = 25
+	for i := 0; i < n; i++ {
+		writeFile(t, dir, fmt.Sprintf("kep-%02d.md", i), validKEPFrontMatter)
+	}
+	writeFile(t, dir, "README.md", "ignored")
+
+	ef := newRealEnhancementFinder(8)
+	out, err := ef.Find(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(*out) != n {
+		t.Fatalf("expected %d proposals, got %d", n, len(*out))
+	}
+}
+
+func TestFindStreamCancellationDoesNotLeakGoroutines(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		writeFile(t, dir, fmt.Sprintf("kep-%02d.md", i), validKEPFrontMatter)
+	}
+
+	before := runtime.NumGoroutine()
+
+	ef := newRealEnhancementFinder(4)
+	ctx, cancel := context.WithCancel(context.Background())
+	proposals, errs := ef.FindStream(ctx, dir)
+	cancel()
+	for proposals != nil || errs != nil {
+		select {
+		case _, ok := <-proposals:
+			if !ok {
+				proposals = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Fatalf("goroutine leak: had %d goroutines before the find, %d after", before, after)
+	}
+}
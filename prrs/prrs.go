@@ -0,0 +1,157 @@
+// Package prrs parses and validates Production Readiness Review (PRR)
+// approval files. A PRR approval file lives alongside a KEP and records,
+// per graduation stage, which PRR approver signed off on the enhancement
+// advancing to that stage.
+package prrs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Stage records the PRR approval for a single graduation stage.
+type Stage struct {
+	Approver string `yaml:"approver"`
+	Jira     string `yaml:"jira,omitempty"`
+	Issue    string `yaml:"issue,omitempty"`
+}
+
+// PRRApproval is the parsed contents of a PRR approval file.
+type PRRApproval struct {
+	Alpha  *Stage `yaml:"alpha,omitempty"`
+	Beta   *Stage `yaml:"beta,omitempty"`
+	Stable *Stage `yaml:"stable,omitempty"`
+
+	Filename string `yaml:"-"`
+
+	unknownStages []string
+}
+
+// stage returns the Stage block for the given stage name, or nil if the
+// PRR approval file does not have one.
+func (p *PRRApproval) stage(name string) *Stage {
+	switch name {
+	case "alpha":
+		return p.Alpha
+	case "beta":
+		return p.Beta
+	case "stable":
+		return p.Stable
+	}
+	return nil
+}
+
+var knownStages = []string{"alpha", "beta", "stable"}
+
+func isKnownStage(name string) bool {
+	for _, s := range knownStages {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Parser extracts a PRRApproval from the YAML front-matter of a PRR
+// approval file, the same way keps.Parser does for proposals.
+type Parser struct {
+}
+
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+func (p *Parser) Parse(in io.Reader) (*PRRApproval, error) {
+	scanner := bufio.NewScanner(in)
+	count := 0
+	metadata := []byte{}
+	for scanner.Scan() {
+		line := scanner.Text() + "\n"
+		if strings.Contains(line, "---") {
+			count++
+			continue
+		}
+		if count == 2 {
+			break
+		}
+		metadata = append(metadata, []byte(line)...)
+
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	approval := &PRRApproval{}
+	if err := yaml.Unmarshal(metadata, approval); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var raw map[string]yaml.MapSlice
+	if err := yaml.Unmarshal(metadata, &raw); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var unknownStages []string
+	for key := range raw {
+		if !isKnownStage(key) {
+			unknownStages = append(unknownStages, key)
+		}
+	}
+	sort.Strings(unknownStages)
+	approval.unknownStages = unknownStages
+
+	return approval, nil
+}
+
+// Validate checks a PRRApproval against the stages a KEP requires approval
+// for and the set of valid PRR approvers for the owning SIG. requiredStages
+// is the set of stages (e.g. "alpha", "beta") the KEP metadata says it has
+// reached; approverSet is the SIG's PRR approver set.
+func Validate(p *PRRApproval, requiredStages, approverSet []string) []error {
+	var field fieldValidator
+	for _, s := range p.unknownStages {
+		field.isKnownStage(s)
+	}
+	for _, stageName := range requiredStages {
+		stage := p.stage(stageName)
+		if stage == nil {
+			field.hasStage(stageName)
+			continue
+		}
+		field.isNonEmpty(stageName+" approver", stage.Approver)
+		if stage.Approver != "" {
+			field.isOneOf(stageName+" approver", stage.Approver, approverSet)
+		}
+	}
+	return []error(field)
+}
+
+type fieldValidator []error
+
+func (fv *fieldValidator) isNonEmpty(field, value string) {
+	if value == "" {
+		*fv = append(*fv, fmt.Errorf("%s cannot be empty", field))
+	}
+}
+
+func (fv *fieldValidator) isOneOf(field, value string, validValues []string) {
+	for _, v := range validValues {
+		if value == v {
+			return
+		}
+	}
+	*fv = append(*fv, fmt.Errorf("'%s' is not a valid %s. Valid options are '%s'", value, field, strings.Join(validValues, "', '")))
+}
+
+func (fv *fieldValidator) hasStage(stage string) {
+	*fv = append(*fv, fmt.Errorf("PRR approval is missing required stage %q", stage))
+}
+
+func (fv *fieldValidator) isKnownStage(stage string) {
+	*fv = append(*fv, fmt.Errorf("%q is not a known PRR stage. Valid stages are '%s'", stage, strings.Join(knownStages, "', '")))
+}
@@ -0,0 +1,139 @@
+package prrs_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chuckha/kepview/prrs"
+)
+
+func TestValidParsing(t *testing.T) {
+	testcases := []struct {
+		name         string
+		fileContents string
+	}{
+		{
+			"simple test",
+			`---
+alpha:
+  approver: "@deads2k"
+beta:
+  approver: "@lavalamp"
+  jira: "KEP-123"
+---`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := prrs.NewParser()
+			out, err := p.Parse(strings.NewReader(tc.fileContents))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if out == nil {
+				t.Fatal("out should not be nil")
+			}
+		})
+	}
+}
+
+func TestPRRValidation(t *testing.T) {
+	testcases := []struct {
+		name           string
+		content        string
+		requiredStages []string
+		approverSet    []string
+		expectedErrors []error
+	}{
+		{
+			name: "valid approval",
+			content: `---
+alpha:
+  approver: "@deads2k"
+---`,
+			requiredStages: []string{"alpha"},
+			approverSet:    []string{"@deads2k"},
+			expectedErrors: nil,
+		},
+		{
+			name: "missing required stage",
+			content: `---
+alpha:
+  approver: "@deads2k"
+---`,
+			requiredStages: []string{"alpha", "beta"},
+			approverSet:    []string{"@deads2k"},
+			expectedErrors: []error{errMissingStage("beta")},
+		},
+		{
+			name: "approver not in approver set",
+			content: `---
+alpha:
+  approver: "@someoneelse"
+---`,
+			requiredStages: []string{"alpha"},
+			approverSet:    []string{"@deads2k"},
+			expectedErrors: []error{errInvalidApprover("@someoneelse", "@deads2k")},
+		},
+		{
+			name: "unknown stage key",
+			content: `---
+alpha:
+  approver: "@deads2k"
+rc:
+  approver: "@deads2k"
+---`,
+			requiredStages: []string{"alpha"},
+			approverSet:    []string{"@deads2k"},
+			expectedErrors: []error{errUnknownStage("rc")},
+		},
+		{
+			name: "multiple unknown stage keys are reported in sorted order",
+			content: `---
+alpha:
+  approver: "@deads2k"
+zeta:
+  approver: "@deads2k"
+rc:
+  approver: "@deads2k"
+---`,
+			requiredStages: []string{"alpha"},
+			approverSet:    []string{"@deads2k"},
+			expectedErrors: []error{errUnknownStage("rc"), errUnknownStage("zeta")},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := prrs.NewParser()
+			approval, err := p.Parse(strings.NewReader(tc.content))
+			if err != nil {
+				t.Fatalf("error parsing PRR approval: %v", err)
+			}
+			got := prrs.Validate(approval, tc.requiredStages, tc.approverSet)
+			if len(got) != len(tc.expectedErrors) {
+				t.Fatalf("expected errors:\n%v\ngot:\n%v", tc.expectedErrors, got)
+			}
+			for i := range got {
+				if got[i].Error() != tc.expectedErrors[i].Error() {
+					t.Errorf("expected error %q, got %q", tc.expectedErrors[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func errMissingStage(stage string) error {
+	return strErr("PRR approval is missing required stage \"" + stage + "\"")
+}
+
+func errInvalidApprover(value, valid string) error {
+	return strErr("'" + value + "' is not a valid " + "alpha approver" + ". Valid options are '" + valid + "'")
+}
+
+func errUnknownStage(stage string) error {
+	return strErr("\"" + stage + "\" is not a known PRR stage. Valid stages are 'alpha', 'beta', 'stable'")
+}
+
+type strErr string
+
+func (s strErr) Error() string { return string(s) }